@@ -0,0 +1,105 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultCacheCapacity bounds the number of distinct expressions Compile will keep compiled at
+// once, so that a long-running process fed unbounded or attacker-controlled expression strings
+// cannot grow the cache without limit.
+const defaultCacheCapacity = 256
+
+// lruCache is a fixed-capacity, least-recently-used cache keyed by string, safe for concurrent
+// use. It backs both the compiled-Path cache below and functions.go's compiled-regexp cache, so
+// that neither can grow without bound when keyed off attacker-influenced input - an expression
+// string for the former, a regular-expression pattern pulled from a document for the latter.
+type lruCache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](capacity int) *lruCache[V] {
+	return &lruCache[V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruEntry[V]).value, true
+}
+
+func (c *lruCache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*lruEntry[V]).value = value
+		return
+	}
+
+	el := c.ll.PushFront(&lruEntry[V]{key: key, value: value})
+	c.items[key] = el
+
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+var defaultPathCache = newLRUCache[*Path](defaultCacheCapacity)
+
+// Compile returns a Path for expr, reusing a previously compiled Path for the same expression
+// string when one is cached. Hot loops that repeatedly evaluate a small, recurring set of path
+// expressions - webhooks, admission controllers, template engines - should prefer Compile over
+// NewPath to avoid re-lexing identical expressions.
+func Compile(expr string) (*Path, error) {
+	if p, ok := defaultPathCache.get(expr); ok {
+		return p, nil
+	}
+	p, err := NewPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	defaultPathCache.put(expr, p)
+	return p, nil
+}
+
+// MustCompile is like Compile but panics if expr fails to compile. It is intended for
+// initializing package-level Path variables from expressions that are known to be valid.
+func MustCompile(expr string) *Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestPathCacheGetPutRoundTrip(t *testing.T) {
+	c := newLRUCache[*Path](2)
+	p := &Path{}
+
+	if _, ok := c.get("$.a"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	c.put("$.a", p)
+	got, ok := c.get("$.a")
+	if !ok || got != p {
+		t.Fatalf("expected to get back the path just put, got %v, %v", got, ok)
+	}
+}
+
+func TestPathCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[*Path](2)
+	pa, pb, pc := &Path{}, &Path{}, &Path{}
+
+	c.put("$.a", pa)
+	c.put("$.b", pb)
+	// Touch $.a so $.b becomes the least recently used entry.
+	if _, ok := c.get("$.a"); !ok {
+		t.Fatal("expected $.a to still be cached")
+	}
+	c.put("$.c", pc)
+
+	if _, ok := c.get("$.b"); ok {
+		t.Fatal("expected $.b to have been evicted as the least recently used entry")
+	}
+	if _, ok := c.get("$.a"); !ok {
+		t.Fatal("expected $.a to still be cached, having been touched most recently")
+	}
+	if _, ok := c.get("$.c"); !ok {
+		t.Fatal("expected $.c to be cached, having just been inserted")
+	}
+}
+
+func TestPathCachePutOverwritesExistingEntryWithoutGrowing(t *testing.T) {
+	c := newLRUCache[*Path](1)
+	pa, pa2 := &Path{}, &Path{}
+
+	c.put("$.a", pa)
+	c.put("$.a", pa2)
+
+	got, ok := c.get("$.a")
+	if !ok || got != pa2 {
+		t.Fatalf("expected the second put to overwrite the first, got %v, %v", got, ok)
+	}
+	if n := c.ll.Len(); n != 1 {
+		t.Fatalf("expected the cache to still hold 1 entry after overwriting, got %d", n)
+	}
+}
+
+func TestPathCacheEvictsAtCapacityBoundary(t *testing.T) {
+	c := newLRUCache[*Path](defaultCacheCapacity)
+	for i := 0; i < defaultCacheCapacity; i++ {
+		c.put(fmt.Sprintf("$.p%d", i), &Path{})
+	}
+	if _, ok := c.get("$.p0"); !ok {
+		t.Fatal("expected the first entry to still be cached at exactly defaultCacheCapacity entries")
+	}
+
+	// One more entry than capacity must evict the least recently used one ($.p0, never touched
+	// above except by the check that just ran, which itself just made it most recently used -
+	// so $.p1 is now the true least recently used entry).
+	c.put("$.overflow", &Path{})
+	if _, ok := c.get("$.p1"); ok {
+		t.Fatal("expected $.p1 to have been evicted once the cache grew past its capacity")
+	}
+	if _, ok := c.get("$.p0"); !ok {
+		t.Fatal("expected $.p0 to remain cached, having been touched most recently")
+	}
+}
+
+func TestPathCacheConcurrentAccess(t *testing.T) {
+	c := newLRUCache[*Path](16)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				expr := fmt.Sprintf("$.g%d.i%d", g, i%8)
+				c.put(expr, &Path{})
+				c.get(expr)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestCompileReusesCachedPath(t *testing.T) {
+	a, err := Compile("$.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Compile("$.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("expected Compile to return the same cached *Path for the same expression")
+	}
+}
+
+func TestMustCompilePanicsOnInvalidExpression(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected MustCompile to panic on an invalid expression")
+		}
+	}()
+	MustCompile("$[")
+}
+
+func TestRegexCacheBoundedWhenPatternComesFromDocument(t *testing.T) {
+	// search()'s second argument may itself be a nodelist query, so the regex pattern compiled
+	// can come from the document being evaluated rather than the path text. A long-running
+	// process evaluating this path against many documents, each with a distinct pattern field,
+	// must not grow regexCache without bound.
+	p, err := NewPath("$.items[?(search(@.name, @.pattern))]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < defaultCacheCapacity*4; i++ {
+		n := &yaml.Node{}
+		if err := yaml.Unmarshal([]byte(fmt.Sprintf("items:\n- name: x\n  pattern: p%d\n", i)), n); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Find(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := regexCache.ll.Len(); n > defaultCacheCapacity {
+		t.Fatalf("expected regexCache to stay at or below defaultCacheCapacity (%d), got %d entries", defaultCacheCapacity, n)
+	}
+}
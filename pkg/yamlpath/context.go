@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"context"
+	"errors"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// EvalOptions bounds the work FindContext will do, so that adversarial input - a deeply nested
+// document combined with an expensive expression such as $..*..* - cannot cause unbounded work.
+// A zero value for any field means that limit is not enforced.
+type EvalOptions struct {
+	// MaxNodesVisited stops evaluation once more than this many nodes have been visited by the
+	// Path's matching process. This bounds not just the nodes ultimately yielded to the caller,
+	// but every node considered along the way, including those visited while a filter evaluates
+	// its own nested sub-path.
+	MaxNodesVisited int
+
+	// MaxRecursionDepth stops evaluation once a recursive-descent (..) segment would descend
+	// deeper than this many levels from the node it was applied to.
+	MaxRecursionDepth int
+
+	// MaxResults stops evaluation once this many matches have been collected.
+	MaxResults int
+}
+
+// ErrEvalLimitExceeded is returned by FindContext when MaxNodesVisited or MaxRecursionDepth is
+// exceeded before evaluation completes.
+var ErrEvalLimitExceeded = errors.New("yamlpath: evaluation limit exceeded")
+
+// evalBudget bounds a single FindContext call's work, and is threaded through every point in a
+// Path's evaluation that can do non-trivial work on its own: each step of composition (compose),
+// each node visited during recursive descent (recurse), and each node a filter evaluates -
+// including a filter's own nested sub-path or function-call evaluation (filter.go,
+// function_call.go). This matters because not all of that evaluation is driven by the caller
+// consuming results: a filter, for example, tests every candidate node, possibly evaluating its own
+// nested sub-path for each, before the Path yields its first match. Checking only between results,
+// as FindContext once did, therefore caught a runaway evaluation far too late. A nil *evalBudget
+// imposes no limit, which is how Find (and everything it calls) behaves.
+type evalBudget struct {
+	ctx      context.Context
+	maxNodes int
+	maxDepth int
+	visited  int
+	err      error
+}
+
+// ok reports whether evaluation may continue, counting the call as one more node visited. Once it
+// has returned false, it continues to do so; the reason is available via err.
+func (b *evalBudget) ok() bool {
+	if b == nil {
+		return true
+	}
+	if b.err != nil {
+		return false
+	}
+	if err := b.ctx.Err(); err != nil {
+		b.err = err
+		return false
+	}
+	b.visited++
+	if b.maxNodes > 0 && b.visited > b.maxNodes {
+		b.err = ErrEvalLimitExceeded
+	}
+	return b.err == nil
+}
+
+// depthOK reports whether a recursive-descent walk may descend to depth, without counting it as a
+// node visit.
+func (b *evalBudget) depthOK(depth int) bool {
+	if b == nil {
+		return true
+	}
+	if b.err != nil {
+		return false
+	}
+	if b.maxDepth > 0 && depth > b.maxDepth {
+		b.err = ErrEvalLimitExceeded
+		return false
+	}
+	return true
+}
+
+// FindContext is like Find, but stops early and returns an error if ctx is cancelled or if opts'
+// limits are exceeded, instead of running an expression to completion regardless of cost. Partial
+// results gathered before the limit was hit are returned alongside the error.
+func (p *Path) FindContext(ctx context.Context, root *yaml.Node, opts EvalOptions) ([]*yaml.Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ev := &evalBudget{ctx: ctx, maxNodes: opts.MaxNodesVisited, maxDepth: opts.MaxRecursionDepth}
+
+	var result []*yaml.Node
+	for n := range p.f(root, root, ev) {
+		result = append(result, n)
+		if opts.MaxResults > 0 && len(result) >= opts.MaxResults {
+			break
+		}
+	}
+
+	if ev.err != nil {
+		return result, ev.err
+	}
+	return result, nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+)
+
+func deeplyNestedDoc(t *testing.T, depth int) string {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("root:\n")
+	for i := 0; i < depth; i++ {
+		sb.WriteString(strings.Repeat("  ", i+1))
+		sb.WriteString("a:\n")
+	}
+	sb.WriteString(strings.Repeat("  ", depth+1))
+	sb.WriteString("leaf: 1\n")
+	return sb.String()
+}
+
+func TestFindContextMaxResults(t *testing.T) {
+	n := mustUnmarshal(t, "items: [1, 2, 3, 4, 5]\n")
+	p, err := yamlpath.NewPath("$.items[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.FindContext(context.Background(), n, yamlpath.EvalOptions{MaxResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected MaxResults to cap the result at 2, got %d", len(got))
+	}
+}
+
+func TestFindContextZeroOptionsMeansUnbounded(t *testing.T) {
+	n := mustUnmarshal(t, "items: [1, 2, 3, 4, 5]\n")
+	p, err := yamlpath.NewPath("$.items[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.FindContext(context.Background(), n, yamlpath.EvalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected all 5 matches with no limits set, got %d", len(got))
+	}
+}
+
+func TestFindContextMaxNodesVisitedExceeded(t *testing.T) {
+	n := mustUnmarshal(t, deeplyNestedDoc(t, 50))
+	p, err := yamlpath.NewPath("$..leaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.FindContext(context.Background(), n, yamlpath.EvalOptions{MaxNodesVisited: 3})
+	if !errors.Is(err, yamlpath.ErrEvalLimitExceeded) {
+		t.Fatalf("expected ErrEvalLimitExceeded, got %v", err)
+	}
+}
+
+func TestFindContextMaxRecursionDepthMatchingActualDepthSucceeds(t *testing.T) {
+	// A regression test: setting MaxRecursionDepth to the document's actual depth must succeed,
+	// not be rejected by an off-by-one in how depth is charged against recursion into leaf nodes.
+	const depth = 10
+	n := mustUnmarshal(t, deeplyNestedDoc(t, depth))
+	p, err := yamlpath.NewPath("$..leaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.FindContext(context.Background(), n, yamlpath.EvalOptions{MaxRecursionDepth: depth + 2})
+	if err != nil {
+		t.Fatalf("expected no error at the document's actual depth, got %v", err)
+	}
+	if len(got) != 1 || got[0].Value != "1" {
+		t.Fatalf("expected to find leaf: 1, got %v", got)
+	}
+}
+
+func TestFindContextMaxRecursionDepthExceeded(t *testing.T) {
+	n := mustUnmarshal(t, deeplyNestedDoc(t, 50))
+	p, err := yamlpath.NewPath("$..leaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.FindContext(context.Background(), n, yamlpath.EvalOptions{MaxRecursionDepth: 3})
+	if !errors.Is(err, yamlpath.ErrEvalLimitExceeded) {
+		t.Fatalf("expected ErrEvalLimitExceeded, got %v", err)
+	}
+}
+
+func TestFindContextBoundsNestedRecursiveDescent(t *testing.T) {
+	// This is the DoS scenario the limits exist for: a recursive descent applied to every node
+	// that another recursive descent yields ($..*..*), over a deeply nested document. Without ev
+	// threaded into that evaluation, this could run to completion - possibly for a long time -
+	// before FindContext's own checks ever had a chance to run.
+	n := mustUnmarshal(t, deeplyNestedDoc(t, 2000))
+	p, err := yamlpath.NewPath("$..*..*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = p.FindContext(context.Background(), n, yamlpath.EvalOptions{MaxNodesVisited: 1000, MaxRecursionDepth: 50})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, yamlpath.ErrEvalLimitExceeded) {
+		t.Fatalf("expected ErrEvalLimitExceeded, got %v", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("FindContext took %v; limits are not bounding nested recursive-descent evaluation", elapsed)
+	}
+}
+
+func TestFindContextRespectsCancelledContext(t *testing.T) {
+	n := mustUnmarshal(t, "items: [1]\n")
+	p, err := yamlpath.NewPath("$.items[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = p.FindContext(ctx, n, yamlpath.EvalOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
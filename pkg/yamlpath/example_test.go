@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
@@ -95,3 +96,102 @@ spec:
 
 	// Output: success
 }
+
+// Example_findWithPaths shows how a linter or refactoring tool can report the normalized
+// JSONPath and source location of each match, rather than just the node itself.
+func Example_findWithPaths() {
+	y := `---
+spec:
+  containers:
+  - name: nginx
+    image: nginx
+  - name: nginy
+    image: nginy
+`
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(y), &n); err != nil {
+		log.Fatalf("cannot unmarshal data: %v", err)
+	}
+
+	p, err := yamlpath.NewPath("$.spec.containers[*].image")
+	if err != nil {
+		log.Fatalf("cannot create path: %v", err)
+	}
+
+	matches, err := p.FindWithPaths(&n)
+	if err != nil {
+		log.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%s = %s (line %d)\n", m.Path, m.Node.Value, m.Line)
+	}
+
+	// Output:
+	// $.spec.containers[0].image = nginx (line 5)
+	// $.spec.containers[1].image = nginy (line 7)
+}
+
+// Example_mustCompile shows how a package-level Path, built once with MustCompile, can be reused
+// across many documents without re-lexing the expression each time.
+func Example_mustCompile() {
+	var imagePath = yamlpath.MustCompile("$..image")
+
+	y := `---
+image: nginx
+`
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(y), &n); err != nil {
+		log.Fatalf("cannot unmarshal data: %v", err)
+	}
+
+	q, err := imagePath.Find(&n)
+	if err != nil {
+		log.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, i := range q {
+		fmt.Println(i.Value)
+	}
+
+	// Output: nginx
+}
+
+// Example_findAll shows how a single compiled Path can be applied across every document in a
+// "---"-separated YAML stream.
+func Example_findAll() {
+	y := `---
+image: nginx
+---
+image: nginy
+`
+	dec := yaml.NewDecoder(strings.NewReader(y))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		docs = append(docs, &doc)
+	}
+
+	p, err := yamlpath.NewPath("$.image")
+	if err != nil {
+		log.Fatalf("cannot create path: %v", err)
+	}
+
+	results, err := p.FindAll(docs)
+	if err != nil {
+		log.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, matches := range results {
+		for _, m := range matches {
+			fmt.Printf("doc %d: %s\n", i, m.Value)
+		}
+	}
+
+	// Output:
+	// doc 0: nginx
+	// doc 1: nginy
+}
@@ -0,0 +1,341 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// builtinParamTypes declares the RFC 9535 parameter types of each built-in filter function, used
+// by validateFunctionCall to report type errors when a path is compiled, rather than only at
+// evaluation time.
+var builtinParamTypes = map[string][]ArgType{
+	"length": {ValueType},
+	"count":  {NodesType},
+	"match":  {ValueType, ValueType},
+	"search": {ValueType, ValueType},
+	"value":  {NodesType},
+}
+
+// builtinReturnTypes declares the RFC 9535 result type of each built-in filter function, used to
+// type-check a function call used as an argument to another function call.
+var builtinReturnTypes = map[string]ArgType{
+	"length": ValueType,
+	"count":  ValueType,
+	"match":  LogicalType,
+	"search": LogicalType,
+	"value":  ValueType,
+}
+
+func (t ArgType) String() string {
+	switch t {
+	case NodesType:
+		return "nodelist"
+	case ValueType:
+		return "value"
+	case LogicalType:
+		return "logical"
+	default:
+		return "unknown"
+	}
+}
+
+// validateFilterTree walks a parsed filter expression looking for calls to filter functions, and
+// reports a compile-time error for any call that names an unregistered function, passes the wrong
+// number of arguments, or passes an argument of a type the function does not accept.
+func validateFilterTree(n *filterNode) error {
+	if n == nil {
+		return nil
+	}
+	if n.lexeme.typ == lexemeFunctionCall {
+		if err := validateFunctionCall(n.lexeme.val); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.children {
+		if err := validateFilterTree(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFunctionCall reports a compile-time error if call is not a well-formed call to a
+// registered filter function with the right number and types of arguments.
+func validateFunctionCall(call string) error {
+	name, argExprs, err := parseFunctionCall(call)
+	if err != nil {
+		return err
+	}
+	fn, ok := lookupFilterFunction(name)
+	if !ok {
+		return fmt.Errorf("yamlpath: unknown filter function %q", name)
+	}
+	if len(argExprs) != fn.Arity {
+		return fmt.Errorf("yamlpath: filter function %q expects %d argument(s), got %d", name, fn.Arity, len(argExprs))
+	}
+
+	paramTypes := builtinParamTypes[name]
+	for i, raw := range argExprs {
+		arg := strings.TrimSpace(raw)
+		_, isCall := peekCallName(arg)
+		switch {
+		case strings.HasPrefix(arg, "@") || strings.HasPrefix(arg, "$"):
+			// newFunctionArgEvaluator builds the same *Path newFunctionCallEvaluator will use at
+			// evaluation time, so a malformed sub-path such as "@.foo[" is rejected here, at
+			// compile time, rather than panicking the first time the path is evaluated.
+			if _, err := newFunctionArgEvaluator(arg); err != nil {
+				return fmt.Errorf("yamlpath: filter function %q: argument %d: %w", name, i+1, err)
+			}
+		case isCall:
+			// validateFunctionCall also rejects a nested call to an unregistered function, which
+			// newFunctionCallEvaluator would otherwise fail on when the filter is built.
+			if err := validateFunctionCall(arg); err != nil {
+				return err
+			}
+		default:
+			if _, err := parseLiteralArg(arg); err != nil {
+				return fmt.Errorf("yamlpath: filter function %q: argument %d: %w", name, i+1, err)
+			}
+		}
+
+		if i < len(paramTypes) {
+			if actual := staticArgType(arg); !argTypeCompatible(actual, paramTypes[i]) {
+				return fmt.Errorf("yamlpath: filter function %q: argument %d: cannot use %s as %s", name, i+1, actual, paramTypes[i])
+			}
+		}
+	}
+	return nil
+}
+
+// staticArgType estimates the RFC 9535 type of a function-call argument expression without
+// evaluating it against any node, so that validateFunctionCall can type-check calls at compile
+// time. A bare path expression (@... or $...) is always a nodelist; a nested call to a built-in
+// function takes its declared return type; anything else, including a nested call to a function
+// added with RegisterFilterFunction, is treated as a value.
+func staticArgType(expr string) ArgType {
+	if strings.HasPrefix(expr, "@") || strings.HasPrefix(expr, "$") {
+		return NodesType
+	}
+	if name, ok := peekCallName(expr); ok {
+		if rt, ok := builtinReturnTypes[name]; ok {
+			return rt
+		}
+	}
+	return ValueType
+}
+
+// argTypeCompatible reports whether a value of type actual may be used where expected is
+// required, per RFC 9535's implicit conversion of a singular-query nodelist to a value or to a
+// logical existence test.
+func argTypeCompatible(actual, expected ArgType) bool {
+	if actual == expected {
+		return true
+	}
+	return actual == NodesType && (expected == ValueType || expected == LogicalType)
+}
+
+// newFunctionCallEvaluator compiles a lexemeFunctionCall token's text, e.g. "length(@.name)",
+// into a function that evaluates the call against a given node and root. The returned function
+// takes an *evalBudget so that a path argument such as @.foo cannot itself run unbounded: a filter
+// function's arguments are evaluated as part of the same budgeted walk as everything else.
+func newFunctionCallEvaluator(call string) (func(node, root *yaml.Node, ev *evalBudget) FilterArg, error) {
+	name, argExprs, err := parseFunctionCall(call)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := lookupFilterFunction(name)
+	if !ok {
+		return nil, fmt.Errorf("yamlpath: unknown filter function %q", name)
+	}
+	if len(argExprs) != fn.Arity {
+		return nil, fmt.Errorf("yamlpath: filter function %q expects %d argument(s), got %d", name, fn.Arity, len(argExprs))
+	}
+
+	argFns := make([]func(node, root *yaml.Node, ev *evalBudget) FilterArg, len(argExprs))
+	for i, raw := range argExprs {
+		argFn, err := newFunctionArgEvaluator(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		argFns[i] = argFn
+	}
+
+	return func(node, root *yaml.Node, ev *evalBudget) FilterArg {
+		args := make([]FilterArg, len(argFns))
+		for i, argFn := range argFns {
+			args[i] = argFn(node, root, ev)
+		}
+		return fn.Fn(args)
+	}, nil
+}
+
+// newFunctionArgEvaluator compiles a single function-call argument expression: a path rooted at
+// the current node (@...) or the document root ($...), a nested call to another registered
+// function, or a literal.
+func newFunctionArgEvaluator(expr string) (func(node, root *yaml.Node, ev *evalBudget) FilterArg, error) {
+	switch {
+	case strings.HasPrefix(expr, "@"):
+		path, err := NewPath(strings.TrimPrefix(expr, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("yamlpath: invalid filter function argument %q: %w", expr, err)
+		}
+		return func(node, root *yaml.Node, ev *evalBudget) FilterArg {
+			nodes := path.find(node, node, ev)
+			return FilterArg{Type: NodesType, Nodes: nodes}
+		}, nil
+
+	case strings.HasPrefix(expr, "$"):
+		path, err := NewPath(strings.TrimPrefix(expr, "$"))
+		if err != nil {
+			return nil, fmt.Errorf("yamlpath: invalid filter function argument %q: %w", expr, err)
+		}
+		return func(node, root *yaml.Node, ev *evalBudget) FilterArg {
+			nodes := path.find(root, root, ev)
+			return FilterArg{Type: NodesType, Nodes: nodes}
+		}, nil
+
+	default:
+		if _, ok := peekCallName(expr); ok {
+			return newFunctionCallEvaluator(expr)
+		}
+		v, err := parseLiteralArg(expr)
+		if err != nil {
+			return nil, fmt.Errorf("yamlpath: invalid filter function argument %q: %w", expr, err)
+		}
+		return func(node, root *yaml.Node, ev *evalBudget) FilterArg {
+			return v
+		}, nil
+	}
+}
+
+// parseLiteralArg parses a function-call argument that is none of a path or a nested call: a
+// quoted string, true, false, null, or a number.
+func parseLiteralArg(expr string) (FilterArg, error) {
+	switch expr {
+	case "true":
+		return FilterArg{Type: ValueType, Value: true}, nil
+	case "false":
+		return FilterArg{Type: ValueType, Value: false}, nil
+	case "null":
+		return FilterArg{Type: ValueType, Value: nil}, nil
+	}
+	if len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0] {
+		return FilterArg{Type: ValueType, Value: expr[1 : len(expr)-1]}, nil
+	}
+	if f, err := strconv.ParseFloat(expr, 64); err == nil {
+		return FilterArg{Type: ValueType, Value: f}, nil
+	}
+	return FilterArg{}, fmt.Errorf("%q is not a valid literal", expr)
+}
+
+// filterArgTruthy converts a function's result to a boolean for use as a standalone filter
+// predicate, e.g. match(@.name, "^ngin[xy]$") used alone rather than compared to something.
+func filterArgTruthy(a FilterArg) bool {
+	switch a.Type {
+	case LogicalType:
+		return a.Logical
+	case NodesType:
+		return len(a.Nodes) > 0
+	default:
+		return a.Value != nil
+	}
+}
+
+// filterArgToTypedValue converts a function's result to the typedValue representation used by
+// filter comparisons, so a function call can appear as either operand of ==, <, etc.
+func filterArgToTypedValue(a FilterArg) typedValue {
+	switch a.Type {
+	case LogicalType:
+		return typedValue{typ: booleanValueType, val: strconv.FormatBool(a.Logical)}
+
+	case NodesType:
+		if len(a.Nodes) == 1 {
+			return typedValueOfNode(a.Nodes[0])
+		}
+		return typedValue{typ: unknownValueType}
+
+	default:
+		switch v := a.Value.(type) {
+		case string:
+			return typedValue{typ: stringValueType, val: v}
+		case float64:
+			return typedValue{typ: floatValueType, val: strconv.FormatFloat(v, 'f', -1, 64)}
+		case bool:
+			return typedValue{typ: booleanValueType, val: strconv.FormatBool(v)}
+		default:
+			return typedValue{typ: unknownValueType}
+		}
+	}
+}
+
+// parseFunctionCall splits a lexemeFunctionCall token's text, e.g. "match(@.name, 'x')", into the
+// called function's name and its raw, unparsed argument expressions.
+func parseFunctionCall(call string) (string, []string, error) {
+	name, ok := peekCallName(call)
+	if !ok {
+		return "", nil, fmt.Errorf("yamlpath: invalid filter function call %q", call)
+	}
+	inner := call[len(name)+1 : len(call)-1]
+	if strings.TrimSpace(inner) == "" {
+		return name, nil, nil
+	}
+	return name, splitArgs(inner), nil
+}
+
+// peekCallName reports the identifier at the start of s, if s consists of that identifier
+// immediately followed by a parenthesized, balanced call expression.
+func peekCallName(s string) (string, bool) {
+	i := 0
+	for i < len(s) {
+		r, w := utf8.DecodeRuneInString(s[i:])
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			i += w
+			continue
+		}
+		break
+	}
+	if i == 0 || i >= len(s) || s[i] != '(' || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	return s[:i], true
+}
+
+// splitArgs splits a function call's argument list on top-level commas, respecting quoted strings
+// and nested parenthesised calls so that e.g. match(@.a, "a,b") and count(foo(@.a, @.b)) split
+// correctly.
+func splitArgs(s string) []string {
+	var args []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			args = append(args, s[start:i])
+			start = i + 1
+		}
+	}
+	args = append(args, s[start:])
+	return args
+}
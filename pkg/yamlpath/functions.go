@@ -0,0 +1,226 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ArgType identifies which of the three RFC 9535 function-extension types a FilterArg carries:
+// a nodelist, a single value, or a logical (boolean) result.
+type ArgType int
+
+const (
+	// NodesType is a nodelist, the result of evaluating a filter-query argument such as @.foo.
+	NodesType ArgType = iota
+	// ValueType is a single JSON-like scalar value (string, float64, bool, or nil for Nothing).
+	ValueType
+	// LogicalType is a boolean, the result of a comparison or existence test.
+	LogicalType
+)
+
+// FilterArg is a single typed argument to, or the typed result of, a filter function extension.
+type FilterArg struct {
+	Type    ArgType
+	Nodes   []*yaml.Node // populated when Type == NodesType
+	Value   interface{}  // populated when Type == ValueType; nil represents the JSONPath "Nothing" value
+	Logical bool         // populated when Type == LogicalType
+}
+
+// FilterFunction is a registered filter function extension: arity is the number of arguments it
+// accepts, and fn computes its typed result from typed arguments.
+type FilterFunction struct {
+	Arity int
+	Fn    func(args []FilterArg) FilterArg
+}
+
+var (
+	filterFunctionsMu sync.RWMutex
+	filterFunctions   = map[string]FilterFunction{}
+)
+
+// RegisterFilterFunction adds a named function extension that can be called from filter
+// expressions, e.g. length(@.foo). name must not already be registered and arity must be
+// non-negative. The lexer (lexer.go) only tokenizes a call as lexemeFunctionCall if name is
+// already registered by the time the path is lexed, so calls must be registered before the paths
+// that use them are compiled.
+func RegisterFilterFunction(name string, arity int, fn func(args []FilterArg) FilterArg) error {
+	if name == "" {
+		return fmt.Errorf("yamlpath: filter function name must not be empty")
+	}
+	if arity < 0 {
+		return fmt.Errorf("yamlpath: filter function %q: arity must not be negative", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("yamlpath: filter function %q: fn must not be nil", name)
+	}
+
+	filterFunctionsMu.Lock()
+	defer filterFunctionsMu.Unlock()
+	if _, exists := filterFunctions[name]; exists {
+		return fmt.Errorf("yamlpath: filter function %q is already registered", name)
+	}
+	filterFunctions[name] = FilterFunction{Arity: arity, Fn: fn}
+	return nil
+}
+
+// lookupFilterFunction returns the function registered under name, if any.
+func lookupFilterFunction(name string) (FilterFunction, bool) {
+	filterFunctionsMu.RLock()
+	defer filterFunctionsMu.RUnlock()
+	f, ok := filterFunctions[name]
+	return f, ok
+}
+
+// regexCache keeps the patterns used by match/search compiled while they remain cached, rather
+// than compiling them once per node visited. It is capacity-bounded, like defaultPathCache in cache.go: match()
+// and search()'s pattern argument may itself be a nodelist query (see stringValue below), so the
+// pattern compiled can come from the document being evaluated rather than the path text, and an
+// unbounded cache keyed on that would let adversarial input grow memory without limit. A pattern
+// evicted from the cache is compiled again the next time it is used.
+var regexCache = newLRUCache[*regexp.Regexp](defaultCacheCapacity)
+
+func compileCached(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.get(pattern); ok {
+		return cached, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.put(pattern, re)
+	return re, nil
+}
+
+func init() {
+	must := func(name string, arity int, fn func(args []FilterArg) FilterArg) {
+		if err := RegisterFilterFunction(name, arity, fn); err != nil {
+			panic(err)
+		}
+	}
+
+	must("length", 1, builtinLength)
+	must("count", 1, builtinCount)
+	must("match", 2, builtinMatch)
+	must("search", 2, builtinSearch)
+	must("value", 1, builtinValue)
+}
+
+// builtinLength implements the RFC 9535 length() function: the number of Unicode scalars in a
+// string, the number of entries in a mapping, or the number of items in a sequence. It returns
+// Nothing for any other argument.
+func builtinLength(args []FilterArg) FilterArg {
+	n, ok := soleNode(args)
+	if ok {
+		return scalarLength(n)
+	}
+	if len(args) == 1 && args[0].Type == ValueType {
+		if s, isStr := args[0].Value.(string); isStr {
+			return FilterArg{Type: ValueType, Value: float64(utf8.RuneCountInString(s))}
+		}
+	}
+	return FilterArg{Type: ValueType, Value: nil}
+}
+
+func scalarLength(n *yaml.Node) FilterArg {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.ShortTag() != strTag {
+			return FilterArg{Type: ValueType, Value: nil}
+		}
+		return FilterArg{Type: ValueType, Value: float64(utf8.RuneCountInString(n.Value))}
+	case yaml.MappingNode:
+		return FilterArg{Type: ValueType, Value: float64(len(n.Content) / 2)}
+	case yaml.SequenceNode:
+		return FilterArg{Type: ValueType, Value: float64(len(n.Content))}
+	default:
+		return FilterArg{Type: ValueType, Value: nil}
+	}
+}
+
+// builtinCount implements the RFC 9535 count() function: the number of nodes in a nodelist.
+func builtinCount(args []FilterArg) FilterArg {
+	if len(args) != 1 || args[0].Type != NodesType {
+		return FilterArg{Type: ValueType, Value: float64(0)}
+	}
+	return FilterArg{Type: ValueType, Value: float64(len(args[0].Nodes))}
+}
+
+// builtinValue implements the RFC 9535 value() function: a single-node nodelist converts to that
+// node's scalar value; any other nodelist size converts to Nothing.
+func builtinValue(args []FilterArg) FilterArg {
+	n, ok := soleNode(args)
+	if !ok || n.Kind != yaml.ScalarNode {
+		return FilterArg{Type: ValueType, Value: nil}
+	}
+	return FilterArg{Type: ValueType, Value: n.Value}
+}
+
+// builtinMatch implements the RFC 9535 match() function: true if the entire string value of the
+// first argument matches the regular expression given by the second.
+func builtinMatch(args []FilterArg) FilterArg {
+	return FilterArg{Type: LogicalType, Logical: regexTest(args, true)}
+}
+
+// builtinSearch implements the RFC 9535 search() function: true if the regular expression given
+// by the second argument matches anywhere within the string value of the first.
+func builtinSearch(args []FilterArg) FilterArg {
+	return FilterArg{Type: LogicalType, Logical: regexTest(args, false)}
+}
+
+func regexTest(args []FilterArg, anchored bool) bool {
+	if len(args) != 2 {
+		return false
+	}
+	subject, ok := stringValue(args[0])
+	if !ok {
+		return false
+	}
+	pattern, ok := stringValue(args[1])
+	if !ok {
+		return false
+	}
+	if anchored {
+		pattern = "^(?:" + pattern + ")$"
+	}
+	re, err := compileCached(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(subject)
+}
+
+// stringValue extracts a string from either a ValueType argument or a single-scalar-node
+// nodelist argument, since filter-query arguments such as @.name arrive as NodesType.
+func stringValue(arg FilterArg) (string, bool) {
+	switch arg.Type {
+	case ValueType:
+		s, ok := arg.Value.(string)
+		return s, ok
+	case NodesType:
+		if len(arg.Nodes) != 1 || arg.Nodes[0].Kind != yaml.ScalarNode {
+			return "", false
+		}
+		return arg.Nodes[0].Value, true
+	default:
+		return "", false
+	}
+}
+
+// soleNode extracts the single node from a one-node nodelist argument, as required by functions
+// such as length() and value() whose first argument is a query result rather than a literal.
+func soleNode(args []FilterArg) (*yaml.Node, bool) {
+	if len(args) != 1 || args[0].Type != NodesType || len(args[0].Nodes) != 1 {
+		return nil, false
+	}
+	return args[0].Nodes[0], true
+}
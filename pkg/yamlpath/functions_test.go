@@ -0,0 +1,129 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+)
+
+func TestFilterFunctionBuiltins(t *testing.T) {
+	n := mustUnmarshal(t, `
+items:
+- name: a
+  tags: [x]
+- name: bb
+  tags: [x, y]
+- name: ccc
+  tags: []
+`)
+
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{"length on string", "$.items[?(length(@.name) > 1)].name", []string{"bb", "ccc"}},
+		{"count on nodelist", "$.items[?(count(@.tags[*]) > 1)].name", []string{"bb"}},
+		{"match anchors the whole string", `$.items[?(match(@.name, "b+"))].name`, []string{"bb"}},
+		{"search finds a substring", `$.items[?(search(@.name, "c"))].name`, []string{"ccc"}},
+		{"value compares a singular query to a literal", `$.items[?(value(@.name) == "a")].name`, []string{"a"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := yamlpath.NewPath(tc.expr)
+			if err != nil {
+				t.Fatalf("NewPath(%q): %v", tc.expr, err)
+			}
+			got, err := p.Find(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d matches, want %d: %v", len(got), len(tc.want), got)
+			}
+			for i, g := range got {
+				if g.Value != tc.want[i] {
+					t.Errorf("match %d: got %q, want %q", i, g.Value, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilterFunctionCompileTimeArityError(t *testing.T) {
+	if _, err := yamlpath.NewPath("$.items[?(length(@.name, @.name) > 1)]"); err == nil {
+		t.Fatal("expected a compile-time error for a wrong number of arguments")
+	}
+}
+
+func TestFilterFunctionCompileTimeTypeError(t *testing.T) {
+	// count() expects a nodelist argument, not a bare literal.
+	if _, err := yamlpath.NewPath(`$.items[?(count("x") > 1)]`); err == nil {
+		t.Fatal("expected a compile-time type error for count(\"x\")")
+	}
+}
+
+func TestFilterFunctionCompileTimeMalformedSubPathError(t *testing.T) {
+	// A regression test: validateFunctionCall previously only checked the static *type* of a
+	// @/$-prefixed argument, not that it actually parses as a path, so a syntactically broken
+	// sub-path compiled successfully and then panicked the first time Find walked into it.
+	for _, expr := range []string{
+		"$.items[?(length(@.foo[) > 2)]",
+		"$.items[?(length(@..) > 2)]",
+		"$.items[?(length(@[) > 2)]",
+	} {
+		if _, err := yamlpath.NewPath(expr); err == nil {
+			t.Errorf("%s: expected a compile-time error for the malformed sub-path", expr)
+		}
+	}
+}
+
+func TestFilterFunctionUnknownNameIsCompileTimeError(t *testing.T) {
+	if _, err := yamlpath.NewPath("$.items[?(nosuchfunction(@.name) > 1)]"); err == nil {
+		t.Fatal("expected a compile-time error for an unregistered function")
+	}
+}
+
+func TestRegisterFilterFunctionCustom(t *testing.T) {
+	if err := yamlpath.RegisterFilterFunction("isEven", 1, func(args []yamlpath.FilterArg) yamlpath.FilterArg {
+		if args[0].Type != yamlpath.NodesType || len(args[0].Nodes) != 1 {
+			return yamlpath.FilterArg{Type: yamlpath.LogicalType, Logical: false}
+		}
+		v, err := strconv.Atoi(args[0].Nodes[0].Value)
+		if err != nil {
+			return yamlpath.FilterArg{Type: yamlpath.LogicalType, Logical: false}
+		}
+		return yamlpath.FilterArg{Type: yamlpath.LogicalType, Logical: v%2 == 0}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	n := mustUnmarshal(t, "items: [1, 2, 3, 4]\n")
+	p, err := yamlpath.NewPath("$.items[?(isEven(@))]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Find(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Value != "2" || got[1].Value != "4" {
+		t.Fatalf("expected [2, 4], got %v", got)
+	}
+}
+
+func TestRegisterFilterFunctionRejectsDuplicateName(t *testing.T) {
+	if err := yamlpath.RegisterFilterFunction("length", 1, func(args []yamlpath.FilterArg) yamlpath.FilterArg {
+		return yamlpath.FilterArg{}
+	}); err == nil {
+		t.Fatal("expected an error re-registering an already-registered function name")
+	}
+}
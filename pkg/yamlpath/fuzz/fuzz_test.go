@@ -1,8 +1,12 @@
 package fuzz
 
 import (
-	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"context"
 	"testing"
+	"time"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"go.yaml.in/yaml/v3"
 )
 
 func FuzzNewPath(f *testing.F) {
@@ -11,5 +15,25 @@ func FuzzNewPath(f *testing.F) {
 		if err != nil && path != nil {
 			t.Fatalf("fuzz test failed with error: %v", err)
 		}
+		if path == nil {
+			return
+		}
+
+		// Compile-time validation alone doesn't exercise evaluation, so also run the compiled
+		// path against a small node derived from the same fuzz input, with tight limits, to
+		// catch unbounded work in FindContext.
+		var node yaml.Node
+		if yaml.Unmarshal(data, &node) != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, _ = path.FindContext(ctx, &node, yamlpath.EvalOptions{
+			MaxNodesVisited:   1000,
+			MaxRecursionDepth: 100,
+			MaxResults:        100,
+		})
 	})
 }
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Match pairs a node found by a Path with its normalized JSONPath and the node's source location,
+// so that tools such as linters and refactoring tools can report diagnostics or emit patches
+// without having to re-derive either from the node pointer alone.
+type Match struct {
+	Node   *yaml.Node
+	Path   string
+	Line   int
+	Column int
+}
+
+// FindWithPaths applies the Path to a YAML node, like Find, but additionally reports the
+// normalized JSONPath (e.g. "$.spec.template.spec.containers[0].image") and the Line/Column of
+// each matched node from the yaml.v3 AST.
+func (p *Path) FindWithPaths(root *yaml.Node) ([]Match, error) {
+	nodes, err := p.Find(root)
+	if err != nil {
+		return nil, err
+	}
+
+	unwrapped := root
+	if unwrapped.Kind == yaml.DocumentNode && len(unwrapped.Content) > 0 {
+		unwrapped = unwrapped.Content[0]
+	}
+
+	// canonicalPaths walks the tree once, up front, rather than re-deriving each matched node's
+	// path with its own root-to-node search: the latter made FindWithPaths superlinear in the
+	// number of matches (each match re-walking from the root, and each ancestor level of that
+	// walk itself re-walking from the root to locate the parent).
+	paths := canonicalPaths(unwrapped)
+
+	matches := make([]Match, 0, len(nodes))
+	for _, n := range nodes {
+		matches = append(matches, Match{
+			Node:   n,
+			Path:   paths[n],
+			Line:   n.Line,
+			Column: n.Column,
+		})
+	}
+	return matches, nil
+}
+
+// canonicalPaths walks root's tree once, returning every descendant's (and root's own) normalized
+// JSONPath keyed by node identity, so that FindWithPaths can look a matched node's path up in
+// O(1) instead of re-deriving it.
+func canonicalPaths(root *yaml.Node) map[*yaml.Node]string {
+	paths := map[*yaml.Node]string{root: "$"}
+
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				child := n.Content[i+1]
+				childPath := path + childSegment(n.Content[i].Value)
+				paths[child] = childPath
+				walk(child, childPath)
+			}
+		case yaml.SequenceNode:
+			for i, child := range n.Content {
+				childPath := fmt.Sprintf("%s[%d]", path, i)
+				paths[child] = childPath
+				walk(child, childPath)
+			}
+		}
+	}
+	walk(root, "$")
+
+	return paths
+}
+
+// childSegment renders a mapping key as a ".name" segment when it is a simple identifier, or as a
+// quoted "['name']" bracket segment otherwise, so the result is always a valid JSONPath.
+func childSegment(key string) string {
+	simple := key != ""
+	for _, r := range key {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			simple = false
+			break
+		}
+	}
+	if simple {
+		return "." + key
+	}
+	return "['" + strings.ReplaceAll(key, "'", `\'`) + "']"
+}
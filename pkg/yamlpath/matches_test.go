@@ -0,0 +1,112 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+)
+
+func TestFindWithPathsRendersCanonicalPaths(t *testing.T) {
+	n := mustUnmarshal(t, "a:\n  b:\n  - c: 1\n  - c: 2\n\"weird key\": 3\n")
+	p, err := yamlpath.NewPath("$..c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := p.FindWithPaths(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if matches[0].Path != "$.a.b[0].c" {
+		t.Errorf("expected $.a.b[0].c, got %s", matches[0].Path)
+	}
+	if matches[1].Path != "$.a.b[1].c" {
+		t.Errorf("expected $.a.b[1].c, got %s", matches[1].Path)
+	}
+}
+
+func TestFindWithPathsQuotesNonIdentifierKeys(t *testing.T) {
+	n := mustUnmarshal(t, "\"weird key\": 1\n")
+	p, err := yamlpath.NewPath("$.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := p.FindWithPaths(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Path != "$['weird key']" {
+		t.Fatalf("expected $['weird key'], got %v", matches)
+	}
+}
+
+func TestFindWithPathsMatchingRoot(t *testing.T) {
+	n := mustUnmarshal(t, "a: 1\n")
+	p, err := yamlpath.NewPath("$")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := p.FindWithPaths(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Path != "$" {
+		t.Fatalf("expected a single match at $, got %v", matches)
+	}
+}
+
+func TestFindWithPathsScalesLinearlyWithMatchCount(t *testing.T) {
+	// A regression test: FindWithPaths previously re-derived each match's path with its own
+	// root-to-node search, making it superlinear in the number of matches. Quadrupling the input
+	// should roughly quadruple the time taken, not multiply it by sixteen (or worse).
+	makeDoc := func(n int) string {
+		s := "items:\n"
+		for i := 0; i < n; i++ {
+			s += fmt.Sprintf("- image: img%d\n", i)
+		}
+		return s
+	}
+
+	p, err := yamlpath.NewPath("$.items[*].image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	measure := func(n int) time.Duration {
+		doc := mustUnmarshal(t, makeDoc(n))
+		start := time.Now()
+		matches, err := p.FindWithPaths(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != n {
+			t.Fatalf("expected %d matches, got %d", n, len(matches))
+		}
+		return time.Since(start)
+	}
+
+	// Warm up (first call pays for one-time setup costs irrelevant to this test).
+	measure(100)
+
+	small := measure(2000)
+	large := measure(8000)
+
+	// A quadratic (or worse) implementation grows by at least 16x (4x the input, squared) here;
+	// a linear one grows by roughly 4x. Allow generous headroom for noise.
+	if large > small*10 {
+		t.Fatalf("FindWithPaths does not appear to scale linearly: 2000 matches took %v, 8000 took %v", small, large)
+	}
+}
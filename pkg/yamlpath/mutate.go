@@ -0,0 +1,310 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Set overwrites the content of every node matched by the Path with the content of value,
+// preserving each matched node's own comments and style. It returns the number of nodes changed.
+func (p *Path) Set(root, value *yaml.Node) (int, error) {
+	matches, err := p.Find(root)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range matches {
+		overwrite(n, value)
+	}
+	return len(matches), nil
+}
+
+// Update applies fn to every node matched by the Path, in place. It returns the number of nodes
+// visited. If fn returns an error for any node, Update stops and returns that error along with the
+// count of nodes successfully updated before the failure.
+func (p *Path) Update(root *yaml.Node, fn func(n *yaml.Node) error) (int, error) {
+	matches, err := p.Find(root)
+	if err != nil {
+		return 0, err
+	}
+	for i, n := range matches {
+		if err := fn(n); err != nil {
+			return i, err
+		}
+	}
+	return len(matches), nil
+}
+
+// Delete removes every node matched by the Path from its enclosing mapping or sequence, splicing
+// the parent's Content so that key/value pairs and sequence elements are removed cleanly. It
+// returns the number of nodes removed. Deleting the document root or a node with no locatable
+// parent is a no-op for that node.
+func (p *Path) Delete(root *yaml.Node) (int, error) {
+	matches, err := p.Find(root)
+	if err != nil {
+		return 0, err
+	}
+
+	unwrapped := root
+	if unwrapped.Kind == yaml.DocumentNode && len(unwrapped.Content) > 0 {
+		unwrapped = unwrapped.Content[0]
+	}
+
+	// Group the index of each match within its parent's Content so that, when a single parent
+	// has several matched children, they can be removed highest-index-first without the earlier
+	// removals shifting the indices of later ones.
+	byParent := map[*yaml.Node][]int{}
+	for _, n := range matches {
+		parent, idx, ok := locate(unwrapped, n)
+		if !ok {
+			continue
+		}
+		if parent.Kind == yaml.MappingNode && idx%2 == 0 {
+			idx++ // a property-name (~) match locates the key; address its pair by the value half
+		}
+		byParent[parent] = append(byParent[parent], idx)
+	}
+
+	deleted := 0
+	for parent, indices := range byParent {
+		sort.Sort(sort.Reverse(sort.IntSlice(indices)))
+		for i, idx := range indices {
+			if i > 0 && idx == indices[i-1] {
+				continue // the same node (or pair) was matched more than once; remove it only once
+			}
+			deleted++
+			switch parent.Kind {
+			case yaml.MappingNode:
+				// idx addresses the value half of the pair; the key immediately precedes it.
+				parent.Content = append(parent.Content[:idx-1], parent.Content[idx+1:]...)
+			case yaml.SequenceNode:
+				parent.Content = append(parent.Content[:idx], parent.Content[idx+1:]...)
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// ErrNotInvertible is returned by Upsert when the Path contains a recursive-descent or filter
+// segment, neither of which can be inverted into a single concrete location to create.
+var ErrNotInvertible = errors.New("yamlpath: path cannot be inverted for upsert")
+
+// Upsert sets the node matched by the Path to value, creating the leaf if its parent already
+// exists but the leaf itself does not: a missing mapping key is added, and a missing sequence
+// index is appended (extending the sequence with null placeholders if necessary). Upsert only
+// supports paths built from the root, dotted child names and integer array subscripts; it
+// returns ErrNotInvertible for paths containing recursive descent, wildcards or filters, since
+// those cannot be inverted into a single location to materialize. Bracketed child names such as
+// ['name'] are not supported and are rejected with an error.
+func (p *Path) Upsert(root *yaml.Node, value *yaml.Node) error {
+	segments, err := simpleSegments(p.expr)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return ErrNotInvertible
+	}
+
+	if n, err := p.Find(root); err == nil && len(n) > 0 {
+		for _, existing := range n {
+			overwrite(existing, value)
+		}
+		return nil
+	}
+
+	cursor := root
+	if cursor.Kind == yaml.DocumentNode && len(cursor.Content) > 0 {
+		cursor = cursor.Content[0]
+	}
+
+	for _, seg := range segments[:len(segments)-1] {
+		next, err := seg.descend(cursor)
+		if err != nil {
+			return err
+		}
+		cursor = next
+	}
+
+	return segments[len(segments)-1].materialize(cursor, value)
+}
+
+// overwrite copies src's content into dst. Content is deep-copied, rather than aliasing src's
+// slice, so that value can safely be reused (e.g. looped over FindAll results) or further mutated
+// after this call without corrupting a previously-overwritten node. src's Anchor is not copied:
+// propagating it verbatim to every matched node would emit the same YAML anchor more than once,
+// which is invalid.
+func overwrite(dst, src *yaml.Node) {
+	dst.Kind = src.Kind
+	dst.Tag = src.Tag
+	dst.Value = src.Value
+	dst.Anchor = ""
+	dst.Alias = src.Alias
+	dst.Content = cloneNodes(src.Content)
+	if dst.Style == 0 {
+		dst.Style = src.Style
+	}
+}
+
+// cloneNode returns a deep copy of n, so that the returned node shares no Content slice (at any
+// depth) with n.
+func cloneNode(n *yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	clone := *n
+	clone.Content = cloneNodes(n.Content)
+	return &clone
+}
+
+func cloneNodes(nodes []*yaml.Node) []*yaml.Node {
+	if nodes == nil {
+		return nil
+	}
+	clones := make([]*yaml.Node, len(nodes))
+	for i, n := range nodes {
+		clones[i] = cloneNode(n)
+	}
+	return clones
+}
+
+// locate performs a depth-first search of root for the node identical to target, returning the
+// container that directly holds it along with target's index within that container's Content.
+// For a mapping value, the returned index addresses the value half of the pair (the key is
+// index-1). locate exists because yaml.Node carries no parent pointer, so matched nodes returned
+// by Find cannot otherwise be spliced out of their enclosing container.
+func locate(root, target *yaml.Node) (parent *yaml.Node, index int, ok bool) {
+	if root == target {
+		return nil, -1, false
+	}
+	for i, c := range root.Content {
+		if c == target {
+			return root, i, true
+		}
+		if p, idx, found := locate(c, target); found {
+			return p, idx, true
+		}
+	}
+	return nil, -1, false
+}
+
+type pathSegment struct {
+	key      string
+	hasKey   bool
+	index    int
+	hasIndex bool
+}
+
+func (s pathSegment) descend(cursor *yaml.Node) (*yaml.Node, error) {
+	switch {
+	case s.hasKey:
+		if cursor.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("yamlpath: cannot descend into %q: not a mapping", s.key)
+		}
+		for i, n := range cursor.Content {
+			if i%2 == 0 && n.Value == s.key {
+				return cursor.Content[i+1], nil
+			}
+		}
+		return nil, fmt.Errorf("yamlpath: upsert requires existing parent %q", s.key)
+	case s.hasIndex:
+		if cursor.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("yamlpath: cannot descend into [%d]: not a sequence", s.index)
+		}
+		if s.index < 0 || s.index >= len(cursor.Content) {
+			return nil, fmt.Errorf("yamlpath: upsert requires existing parent [%d]", s.index)
+		}
+		return cursor.Content[s.index], nil
+	default:
+		return nil, ErrNotInvertible
+	}
+}
+
+// simpleSegments parses the subset of JSONPath that Upsert can invert: the root followed by
+// dotted child names and integer array subscripts. Recursive descent, wildcards and filters have
+// no single concrete location to create, so they are rejected with ErrNotInvertible. A bracketed
+// child name such as ['name'] is rejected as a non-integer subscript.
+func simpleSegments(expr string) ([]pathSegment, error) {
+	if strings.Contains(expr, "..") || strings.ContainsAny(expr, "*?") {
+		return nil, ErrNotInvertible
+	}
+
+	rest := strings.TrimPrefix(expr, "$")
+	var segments []pathSegment
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			name := rest
+			if end != -1 {
+				name = rest[:end]
+				rest = rest[end:]
+			} else {
+				rest = ""
+			}
+			if name == "" {
+				return nil, fmt.Errorf("yamlpath: invalid path %q for upsert", expr)
+			}
+			segments = append(segments, pathSegment{key: name, hasKey: true})
+
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("yamlpath: invalid path %q for upsert", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("yamlpath: upsert only supports integer subscripts, got %q", inner)
+			}
+			segments = append(segments, pathSegment{index: idx, hasIndex: true})
+
+		default:
+			return nil, fmt.Errorf("yamlpath: invalid path %q for upsert", expr)
+		}
+	}
+	return segments, nil
+}
+
+func (s pathSegment) materialize(cursor, value *yaml.Node) error {
+	switch {
+	case s.hasKey:
+		if cursor.Kind != yaml.MappingNode {
+			return fmt.Errorf("yamlpath: cannot set key %q: not a mapping", s.key)
+		}
+		for i, n := range cursor.Content {
+			if i%2 == 0 && n.Value == s.key {
+				overwrite(cursor.Content[i+1], value)
+				return nil
+			}
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s.key}
+		dst := &yaml.Node{}
+		overwrite(dst, value)
+		cursor.Content = append(cursor.Content, key, dst)
+		return nil
+	case s.hasIndex:
+		if cursor.Kind != yaml.SequenceNode {
+			return fmt.Errorf("yamlpath: cannot set index [%d]: not a sequence", s.index)
+		}
+		for len(cursor.Content) <= s.index {
+			cursor.Content = append(cursor.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"})
+		}
+		overwrite(cursor.Content[s.index], value)
+		return nil
+	default:
+		return ErrNotInvertible
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"go.yaml.in/yaml/v3"
+)
+
+func mustUnmarshal(t *testing.T, y string) *yaml.Node {
+	t.Helper()
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(y), &n); err != nil {
+		t.Fatalf("cannot unmarshal data: %v", err)
+	}
+	return &n
+}
+
+func mustMarshal(t *testing.T, n *yaml.Node) string {
+	t.Helper()
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		t.Fatalf("cannot marshal data: %v", err)
+	}
+	return string(out)
+}
+
+func TestSet(t *testing.T) {
+	n := mustUnmarshal(t, "containers:\n- image: nginx\n- image: nginy\n")
+	p, err := yamlpath.NewPath("$.containers[*].image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "example.com/pinned"}
+	count, err := p.Set(n, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 nodes changed, got %d", count)
+	}
+
+	got := mustMarshal(t, n)
+	want := "containers:\n    - image: example.com/pinned\n    - image: example.com/pinned\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetReusedValueDoesNotAliasAcrossMatches(t *testing.T) {
+	// A regression test for overwrite() previously aliasing src.Content across every destination:
+	// mutating one matched node's content must not be visible through the other.
+	n := mustUnmarshal(t, "a:\n  items: []\nb:\n  items: []\n")
+	p, err := yamlpath.NewPath("$.*.items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := mustUnmarshal(t, "[x]").Content[0]
+	if _, err := p.Set(n, value); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := yamlpath.MustCompile("$.*.items").Find(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(items))
+	}
+	items[0].Content[0].Value = "mutated"
+	if items[1].Content[0].Value == "mutated" {
+		t.Fatal("mutating one matched node's content affected the other: Content slice is shared")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	n := mustUnmarshal(t, "items:\n- 1\n- 2\n- 3\n")
+	p, err := yamlpath.NewPath("$.items[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := p.Update(n, func(node *yaml.Node) error {
+		node.Value = node.Value + "0"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 nodes visited, got %d", count)
+	}
+
+	got := mustMarshal(t, n)
+	want := "items:\n    - 10\n    - 20\n    - 30\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateStopsAtFirstError(t *testing.T) {
+	n := mustUnmarshal(t, "items:\n- 1\n- 2\n- 3\n")
+	p, err := yamlpath.NewPath("$.items[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errBoom := errors.New("boom")
+	count, err := p.Update(n, func(node *yaml.Node) error {
+		if node.Value == "2" {
+			return errBoom
+		}
+		node.Value = node.Value + "0"
+		return nil
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 node updated before the failure, got %d", count)
+	}
+	if n.Content[0].Content[1].Content[0].Value != "10" {
+		t.Fatalf("expected the first item to have been updated before the failure")
+	}
+}
+
+func TestDeleteMultipleSiblingsFromSameParent(t *testing.T) {
+	// Deleting several matches under the same mapping/sequence parent must splice highest-index
+	// first so that earlier removals don't shift the indices of later ones.
+	n := mustUnmarshal(t, "items:\n- keep: false\n  n: 1\n- keep: true\n  n: 2\n- keep: false\n  n: 3\n- keep: false\n  n: 4\n")
+	p, err := yamlpath.NewPath("$.items[?(@.keep == false)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := p.Delete(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 nodes deleted, got %d", count)
+	}
+
+	remaining, err := yamlpath.MustCompile("$.items[*].n").Find(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].Value != "2" {
+		t.Fatalf("expected only item n=2 to remain, got %v", remaining)
+	}
+}
+
+func TestDeleteMappingKey(t *testing.T) {
+	n := mustUnmarshal(t, "a: 1\nb: 2\nc: 3\n")
+	p, err := yamlpath.NewPath("$.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := p.Delete(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 node deleted, got %d", count)
+	}
+
+	got := mustMarshal(t, n)
+	want := "a: 1\nc: 3\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpsertCreatesMissingKey(t *testing.T) {
+	n := mustUnmarshal(t, "a: 1\n")
+	p, err := yamlpath.NewPath("$.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "2"}
+	if err := p.Upsert(n, value); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustMarshal(t, n)
+	want := "a: 1\nb: 2\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpsertCreatingNewKeyDoesNotAliasAcrossCalls(t *testing.T) {
+	// A regression test mirroring TestSetReusedValueDoesNotAliasAcrossMatches: Upsert's new-key
+	// path previously appended the caller's value node by reference instead of going through
+	// overwrite() like every other mutation path, so reusing the same value node across two
+	// Upsert calls that each create a key left both mapping values pointing at the same node.
+	n := mustUnmarshal(t, "a: {}\nb: {}\n")
+	pa, err := yamlpath.NewPath("$.a.newkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := yamlpath.NewPath("$.b.newkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := mustUnmarshal(t, "[x]").Content[0]
+	if err := pa.Upsert(n, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.Upsert(n, value); err != nil {
+		t.Fatal(err)
+	}
+
+	aNewKey, err := yamlpath.MustCompile("$.a.newkey").Find(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bNewKey, err := yamlpath.MustCompile("$.b.newkey").Find(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aNewKey) != 1 || len(bNewKey) != 1 {
+		t.Fatalf("expected both keys to have been created, got a=%v b=%v", aNewKey, bNewKey)
+	}
+
+	aNewKey[0].Content[0].Value = "mutated"
+	if bNewKey[0].Content[0].Value == "mutated" {
+		t.Fatal("mutating $.a.newkey's content affected $.b.newkey: Content slice is shared")
+	}
+}
+
+func TestUpsertOverwritesExistingKey(t *testing.T) {
+	n := mustUnmarshal(t, "a: 1\n")
+	p, err := yamlpath.NewPath("$.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "2"}
+	if err := p.Upsert(n, value); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustMarshal(t, n)
+	want := "a: 2\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpsertExtendsSequenceWithNullPlaceholders(t *testing.T) {
+	n := mustUnmarshal(t, "items:\n- 1\n")
+	p, err := yamlpath.NewPath("$.items[3]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "9"}
+	if err := p.Upsert(n, value); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustMarshal(t, n)
+	want := "items:\n    - 1\n    - null\n    - null\n    - 9\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpsertRejectsUninvertiblePaths(t *testing.T) {
+	for _, expr := range []string{"$..a", "$.items[*]", "$.items[?(@.a == 1)]"} {
+		p, err := yamlpath.NewPath(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := mustUnmarshal(t, "items:\n- a: 1\n")
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "2"}
+		if err := p.Upsert(n, value); !errors.Is(err, yamlpath.ErrNotInvertible) {
+			t.Errorf("%s: expected ErrNotInvertible, got %v", expr, err)
+		}
+	}
+}
+
+func TestUpsertRejectsUninvertiblePathsEvenWhenAlreadyMatching(t *testing.T) {
+	// A regression test: Upsert must reject an uninvertible Path even when a match already
+	// exists, rather than only checking invertibility when it falls through to materialization.
+	n := mustUnmarshal(t, "items:\n- a: 1\n- a: 1\n")
+	p, err := yamlpath.NewPath("$.items[*].a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "2"}
+	if err := p.Upsert(n, value); !errors.Is(err, yamlpath.ErrNotInvertible) {
+		t.Fatalf("expected ErrNotInvertible, got %v", err)
+	}
+
+	// And the existing matches must be left untouched.
+	got := mustMarshal(t, n)
+	want := "items:\n    - a: 1\n    - a: 1\n"
+	if got != want {
+		t.Fatalf("Upsert must not overwrite matches when the path is uninvertible; got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -18,21 +18,31 @@ import (
 
 // Path is a compiled YAML path expression.
 type Path struct {
-	f func(node, root *yaml.Node) iter.Seq[*yaml.Node]
+	f    func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node]
+	expr string
 }
 
 // Find applies the Path to a YAML node and returns the addresses of the subnodes which match the Path.
 func (p *Path) Find(node *yaml.Node) ([]*yaml.Node, error) {
-	return p.find(node, node), nil // currently, errors are not possible
+	return p.find(node, node, nil), nil // currently, errors are not possible
 }
 
-func (p *Path) find(node, root *yaml.Node) []*yaml.Node {
-	return slices.Collect(p.f(node, root))
+// find is Find's underlying implementation, additionally threading an evalBudget through the
+// whole evaluation so FindContext's cancellation and limits are checked incrementally, rather than
+// only once the (possibly very expensive) computation below has already run to completion. A nil
+// ev imposes no limit, which is how Find (above) behaves.
+func (p *Path) find(node, root *yaml.Node, ev *evalBudget) []*yaml.Node {
+	return slices.Collect(p.f(node, root, ev))
 }
 
 // NewPath constructs a Path from a string expression.
 func NewPath(path string) (*Path, error) {
-	return newPath(lex("Path lexer", path))
+	p, err := newPath(lex("Path lexer", path))
+	if err != nil {
+		return nil, err
+	}
+	p.expr = path
+	return p, nil
 }
 
 func newPath(l *lexer) (*Path, error) {
@@ -51,11 +61,11 @@ func newPath(l *lexer) (*Path, error) {
 		if err != nil {
 			return nil, err
 		}
-		return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+		return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 			if node.Kind == yaml.DocumentNode {
 				node = node.Content[0]
 			}
-			return compose(lift(node), subPath, root)
+			return compose(lift(node), subPath, root, ev)
 		}), nil
 
 	case lexemeRecursiveDescent:
@@ -67,18 +77,18 @@ func newPath(l *lexer) (*Path, error) {
 		switch childName {
 		case "*":
 			// includes all nodes, not just mapping nodes
-			return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
-				return compose(recurse(node), allChildrenThen(subPath), root)
+			return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
+				return compose(recurse(ev, 0, node), allChildrenThen(subPath), root, ev)
 			}), nil
 
 		case "":
-			return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
-				return compose(recurse(node), subPath, root)
+			return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
+				return compose(recurse(ev, 0, node), subPath, root, ev)
 			}), nil
 
 		default:
-			return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
-				return compose(recurse(node), childThen(childName, subPath), root)
+			return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
+				return compose(recurse(ev, 0, node), childThen(childName, subPath), root, ev)
 			}), nil
 		}
 
@@ -151,9 +161,9 @@ func newPath(l *lexer) (*Path, error) {
 			return nil, err
 		}
 		if recursive {
-			return recursiveFilterThen(filterLexemes, subPath), nil
+			return recursiveFilterThen(filterLexemes, subPath)
 		}
-		return filterThen(filterLexemes, subPath), nil
+		return filterThen(filterLexemes, subPath)
 	case lexemePropertyName:
 		subPath, err := newPath(l)
 		if err != nil {
@@ -184,51 +194,76 @@ func newPath(l *lexer) (*Path, error) {
 	return nil, errors.New("invalid path syntax")
 }
 
-func identity(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+func identity(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 	if node.Kind == 0 {
 		return lift()
 	}
 	return lift(node)
 }
 
-func empty(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+func empty(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 	return lift()
 }
 
-func compose(i iter.Seq[*yaml.Node], p *Path, root *yaml.Node) iter.Seq[*yaml.Node] {
-	its := []iter.Seq[*yaml.Node]{}
-	for a := range i {
-		its = append(its, p.f(a, root))
+// compose applies p to every node produced by i, lazily: p is only invoked, and i is only
+// advanced, as the returned iterator is itself consumed. This matters for bounding cost, since i
+// may be an arbitrarily large or expensive recursive-descent walk (see recurse) - composing
+// eagerly here would run that walk, and every nested p.f call it feeds, to completion before the
+// caller (e.g. FindContext) gets a chance to check ev between results.
+func compose(i iter.Seq[*yaml.Node], p *Path, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
+	return func(yield func(*yaml.Node) bool) {
+		for a := range i {
+			if !ev.ok() {
+				return
+			}
+			if !yieldAll(p.f(a, root, ev), yield) {
+				return
+			}
+		}
 	}
-	return flatten(its...)
 }
 
-func new(f func(node, root *yaml.Node) iter.Seq[*yaml.Node]) *Path {
+// yieldAll drains seq into yield, stopping early if either seq stops itself or yield asks to
+// stop. It reports whether the caller should keep going, since iter.Seq's underlying function type
+// has no return value of its own to report that.
+func yieldAll(seq iter.Seq[*yaml.Node], yield func(*yaml.Node) bool) bool {
+	cont := true
+	seq(func(n *yaml.Node) bool {
+		if !yield(n) {
+			cont = false
+			return false
+		}
+		return true
+	})
+	return cont
+}
+
+func new(f func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node]) *Path {
 	return &Path{f: f}
 }
 
 func propertyNameChildThen(childName string, p *Path) *Path {
 	childName = unescape(childName)
 
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 		for i, n := range node.Content {
 			if i%2 == 0 && n.Value == childName {
-				return compose(lift(node.Content[i]), p, root)
+				return compose(lift(node.Content[i]), p, root, ev)
 			}
 		}
-		return empty(node, root)
+		return empty(node, root, ev)
 	})
 }
 
 func propertyNameBracketChildThen(childNames string, p *Path) *Path {
 	unquotedChildren := bracketChildNames(childNames)
 
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 		its := []iter.Seq[*yaml.Node]{}
 		for _, childName := range unquotedChildren {
@@ -238,23 +273,23 @@ func propertyNameBracketChildThen(childNames string, p *Path) *Path {
 				}
 			}
 		}
-		return compose(flatten(its...), p, root)
+		return compose(flatten(its...), p, root, ev)
 	})
 }
 
 func propertyNameArraySubscriptThen(subscript string, p *Path) *Path {
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind == yaml.MappingNode && subscript == "*" {
 			its := []iter.Seq[*yaml.Node]{}
 			for i, n := range node.Content {
 				if i%2 != 0 {
 					continue // skip child values
 				}
-				its = append(its, compose(lift(n), p, root))
+				its = append(its, compose(lift(n), p, root, ev))
 			}
 			return flatten(its...)
 		}
-		return empty(node, root)
+		return empty(node, root, ev)
 	})
 }
 
@@ -264,16 +299,16 @@ func childThen(childName string, p *Path) *Path {
 	}
 	childName = unescape(childName)
 
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 		for i, n := range node.Content {
 			if i%2 == 0 && n.Value == childName {
-				return compose(lift(node.Content[i+1]), p, root)
+				return compose(lift(node.Content[i+1]), p, root, ev)
 			}
 		}
-		return empty(node, root)
+		return empty(node, root, ev)
 	})
 }
 
@@ -339,9 +374,9 @@ func balanced(c string, q rune) bool {
 func bracketChildThen(childNames string, p *Path) *Path {
 	unquotedChildren := bracketChildNames(childNames)
 
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind != yaml.MappingNode {
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 		its := []iter.Seq[*yaml.Node]{}
 		for _, childName := range unquotedChildren {
@@ -351,7 +386,7 @@ func bracketChildThen(childNames string, p *Path) *Path {
 				}
 			}
 		}
-		return compose(flatten(its...), p, root)
+		return compose(flatten(its...), p, root, ev)
 	})
 }
 
@@ -376,7 +411,7 @@ func unescape(raw string) string {
 }
 
 func allChildrenThen(p *Path) *Path {
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		switch node.Kind {
 		case yaml.MappingNode:
 			its := []iter.Seq[*yaml.Node]{}
@@ -384,37 +419,37 @@ func allChildrenThen(p *Path) *Path {
 				if i%2 == 0 {
 					continue // skip child names
 				}
-				its = append(its, compose(lift(n), p, root))
+				its = append(its, compose(lift(n), p, root, ev))
 			}
 			return flatten(its...)
 
 		case yaml.SequenceNode:
 			its := []iter.Seq[*yaml.Node]{}
 			for i := 0; i < len(node.Content); i++ {
-				its = append(its, compose(lift(node.Content[i]), p, root))
+				its = append(its, compose(lift(node.Content[i]), p, root, ev))
 			}
 			return flatten(its...)
 
 		default:
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 	})
 }
 
 func arraySubscriptThen(subscript string, p *Path) *Path {
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		if node.Kind == yaml.MappingNode && subscript == "*" {
 			its := []iter.Seq[*yaml.Node]{}
 			for i, n := range node.Content {
 				if i%2 == 0 {
 					continue // skip child names
 				}
-				its = append(its, compose(lift(n), p, root))
+				its = append(its, compose(lift(n), p, root, ev))
 			}
 			return flatten(its...)
 		}
 		if node.Kind != yaml.SequenceNode {
-			return empty(node, root)
+			return empty(node, root, ev)
 		}
 
 		slice, err := slice(subscript, len(node.Content))
@@ -425,48 +460,61 @@ func arraySubscriptThen(subscript string, p *Path) *Path {
 		its := []iter.Seq[*yaml.Node]{}
 		for _, s := range slice {
 			if s >= 0 && s < len(node.Content) {
-				its = append(its, compose(lift(node.Content[s]), p, root))
+				its = append(its, compose(lift(node.Content[s]), p, root, ev))
 			}
 		}
 		return flatten(its...)
 	})
 }
 
-func filterThen(filterLexemes []lexeme, p *Path) *Path {
-	filter := newFilter(newFilterNode(filterLexemes))
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+func filterThen(filterLexemes []lexeme, p *Path) (*Path, error) {
+	tree := newFilterNode(filterLexemes)
+	if err := validateFilterTree(tree); err != nil {
+		return nil, err
+	}
+	filter := newFilter(tree)
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		its := []iter.Seq[*yaml.Node]{}
 		if node.Kind == yaml.SequenceNode {
 			for _, c := range node.Content {
-				if filter(c, root) {
-					its = append(its, compose(lift(c), p, root))
+				if !ev.ok() {
+					break
+				}
+				if filter(c, root, ev) {
+					its = append(its, compose(lift(c), p, root, ev))
 				}
 			}
 		} else {
-			if filter(node, root) {
-				its = append(its, compose(lift(node), p, root))
+			if ev.ok() && filter(node, root, ev) {
+				its = append(its, compose(lift(node), p, root, ev))
 			}
 		}
 		return flatten(its...)
-	})
+	}), nil
 }
 
-func recursiveFilterThen(filterLexemes []lexeme, p *Path) *Path {
-	filter := newFilter(newFilterNode(filterLexemes))
-	return new(func(node, root *yaml.Node) iter.Seq[*yaml.Node] {
+func recursiveFilterThen(filterLexemes []lexeme, p *Path) (*Path, error) {
+	tree := newFilterNode(filterLexemes)
+	if err := validateFilterTree(tree); err != nil {
+		return nil, err
+	}
+	filter := newFilter(tree)
+	return new(func(node, root *yaml.Node, ev *evalBudget) iter.Seq[*yaml.Node] {
 		its := []iter.Seq[*yaml.Node]{}
 
-		if filter(node, root) {
-			its = append(its, compose(lift(node), p, root))
+		if ev.ok() && filter(node, root, ev) {
+			its = append(its, compose(lift(node), p, root, ev))
 		}
 		return flatten(its...)
-	})
+	}), nil
 }
 
 func flatten(i ...iter.Seq[*yaml.Node]) iter.Seq[*yaml.Node] {
 	return func(yield func(*yaml.Node) bool) {
 		for _, next := range i {
-			next(yield)
+			if !yieldAll(next, yield) {
+				return
+			}
 		}
 	}
 }
@@ -475,10 +523,25 @@ func lift(nodes ...*yaml.Node) iter.Seq[*yaml.Node] {
 	return slices.Values(nodes)
 }
 
-func recurse(nodes ...*yaml.Node) iter.Seq[*yaml.Node] {
+// recurse lazily yields every node in nodes's subtrees, depth-first, followed by each of nodes
+// itself - the traversal performed by a recursive-descent (..) path segment. depth is checked
+// against EvalOptions.MaxRecursionDepth before descending further, but only when there is
+// something to descend into; nodes is empty at every leaf, and checking against an empty call
+// would reject documents no deeper than the configured limit. Every node recurse yields passes
+// back through compose, which is what actually charges it against MaxNodesVisited - recurse itself
+// does not also charge ev.ok, since each node would otherwise be counted twice.
+func recurse(ev *evalBudget, depth int, nodes ...*yaml.Node) iter.Seq[*yaml.Node] {
 	return func(yield func(*yaml.Node) bool) {
+		if len(nodes) == 0 {
+			return
+		}
+		if !ev.depthOK(depth) {
+			return
+		}
 		for _, n := range nodes {
-			recurse(n.Content...)(yield)
+			if !yieldAll(recurse(ev, depth+1, n.Content...), yield) {
+				return
+			}
 			if !yield(n) {
 				return
 			}
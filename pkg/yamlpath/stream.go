@@ -0,0 +1,56 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath
+
+import (
+	"errors"
+	"io"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FindAll applies the Path to each document in docs and returns the matches for each, in order,
+// so that a single compiled Path can be used across an entire "---"-separated YAML stream without
+// hand-rolling a per-document loop.
+func (p *Path) FindAll(docs []*yaml.Node) ([][]*yaml.Node, error) {
+	results := make([][]*yaml.Node, 0, len(docs))
+	for _, doc := range docs {
+		found, err := p.Find(doc)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found)
+	}
+	return results, nil
+}
+
+// FindStream decodes dec one document at a time, applying the Path to each as it is decoded and
+// invoking yield with the zero-based document index and every matched node. Decoding stops, and
+// FindStream returns nil, as soon as yield returns false. This keeps memory bounded when applying
+// a Path across very large multi-document streams, such as Kubernetes manifest bundles or Helm
+// renderings, since documents are never all held in memory at once.
+func (p *Path) FindStream(dec *yaml.Decoder, yield func(docIndex int, node *yaml.Node) bool) error {
+	for docIndex := 0; ; docIndex++ {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		nodes, err := p.Find(&doc)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			if !yield(docIndex, n) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2020 VMware, Inc.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package yamlpath_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vmware-labs/yaml-jsonpath/pkg/yamlpath"
+	"go.yaml.in/yaml/v3"
+)
+
+func decodeAll(t *testing.T, y string) []*yaml.Node {
+	t.Helper()
+	dec := yaml.NewDecoder(strings.NewReader(y))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		docs = append(docs, &doc)
+	}
+	return docs
+}
+
+func TestFindAllAcrossDocuments(t *testing.T) {
+	docs := decodeAll(t, "image: nginx\n---\nimage: nginy\n---\nother: true\n")
+
+	p, err := yamlpath.NewPath("$.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := p.FindAll(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 per-document results, got %d", len(results))
+	}
+	if len(results[0]) != 1 || results[0][0].Value != "nginx" {
+		t.Fatalf("doc 0: expected [nginx], got %v", results[0])
+	}
+	if len(results[1]) != 1 || results[1][0].Value != "nginy" {
+		t.Fatalf("doc 1: expected [nginy], got %v", results[1])
+	}
+	if len(results[2]) != 0 {
+		t.Fatalf("doc 2: expected no matches, got %v", results[2])
+	}
+}
+
+func TestFindStreamVisitsEveryDocumentInOrder(t *testing.T) {
+	y := "image: nginx\n---\nimage: nginy\n---\nimage: redis\n"
+
+	p, err := yamlpath.NewPath("$.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type match struct {
+		docIndex int
+		value    string
+	}
+	var got []match
+	dec := yaml.NewDecoder(strings.NewReader(y))
+	if err := p.FindStream(dec, func(docIndex int, node *yaml.Node) bool {
+		got = append(got, match{docIndex, node.Value})
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []match{{0, "nginx"}, {1, "nginy"}, {2, "redis"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFindStreamStopsWhenYieldReturnsFalse(t *testing.T) {
+	y := "image: nginx\n---\nimage: nginy\n---\nimage: redis\n"
+
+	p, err := yamlpath.NewPath("$.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []string
+	dec := yaml.NewDecoder(strings.NewReader(y))
+	if err := p.FindStream(dec, func(docIndex int, node *yaml.Node) bool {
+		seen = append(seen, node.Value)
+		return docIndex < 1
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected FindStream to stop after 2 documents, got %v", seen)
+	}
+}
+
+func TestFindStreamPropagatesDecodeError(t *testing.T) {
+	p, err := yamlpath.NewPath("$.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader("image: [unterminated\n"))
+	err = p.FindStream(dec, func(docIndex int, node *yaml.Node) bool {
+		t.Fatal("yield must not be called when decoding fails")
+		return true
+	})
+	if err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("expected a non-EOF decode error, got %v", err)
+	}
+}